@@ -0,0 +1,150 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/hlubek/metaprogramming-go/internal/gencode"
+)
+
+const squirrelImportPath = "github.com/Masterminds/squirrel"
+
+var colrefAnalyzer = &analysis.Analyzer{
+	Name:     "colref",
+	Doc:      "reports column string literals in squirrel.Eq/SetMap/Select calls that no col tag declares",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runColref,
+}
+
+func runColref(pass *analysis.Pass) (interface{}, error) {
+	knownCols := collectKnownCols(pass.Pkg)
+
+	checkLit := func(lit ast.Expr) {
+		basicLit, ok := lit.(*ast.BasicLit)
+		if !ok || basicLit.Kind != token.STRING {
+			return
+		}
+		col, err := strconv.Unquote(basicLit.Value)
+		if err != nil {
+			return
+		}
+		if _, ok := knownCols[col]; !ok {
+			pass.Reportf(basicLit.Pos(), "column %q is not declared by any col tag", col)
+		}
+	}
+
+	checkMapLitKeys := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.CompositeLit)
+		if !ok {
+			return
+		}
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				checkLit(kv.Key)
+			}
+		}
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CompositeLit)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch node := n.(type) {
+		case *ast.CompositeLit:
+			// squirrel.Eq{"col": value, ...}
+			named, ok := pass.TypesInfo.TypeOf(node).(*types.Named)
+			if !ok || !isSquirrelType(named, "Eq") {
+				return
+			}
+			for _, elt := range node.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					checkLit(kv.Key)
+				}
+			}
+
+		case *ast.CallExpr:
+			sel, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return
+			}
+			fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+			if !ok || fn.Pkg() == nil || fn.Pkg().Path() != squirrelImportPath {
+				return
+			}
+			switch fn.Name() {
+			case "Select":
+				// squirrel.Select("col1", "col2", ...)
+				for _, arg := range node.Args {
+					checkLit(arg)
+				}
+			case "SetMap":
+				// builder.SetMap(map[string]interface{}{"col": value, ...})
+				if len(node.Args) == 1 {
+					checkMapLitKeys(node.Args[0])
+				}
+			}
+		}
+	})
+
+	return nil, nil
+}
+
+func isSquirrelType(named *types.Named, name string) bool {
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == squirrelImportPath && obj.Name() == name
+}
+
+// collectKnownCols gathers every col tag value declared by a struct
+// reachable from pkg, directly or through an import, so colref can tell a
+// typo or renamed column from a column some other struct still declares.
+func collectKnownCols(pkg *types.Package) map[string]struct{} {
+	cols := make(map[string]struct{})
+	visited := make(map[*types.Package]bool)
+
+	var visit func(p *types.Package)
+	visit = func(p *types.Package) {
+		if p == nil || visited[p] {
+			return
+		}
+		visited[p] = true
+
+		for _, name := range p.Scope().Names() {
+			typeName, ok := p.Scope().Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if structType, ok := typeName.Type().Underlying().(*types.Struct); ok {
+				collectStructCols(structType, cols, make(map[*types.Struct]bool))
+			}
+		}
+		for _, imp := range p.Imports() {
+			visit(imp)
+		}
+	}
+	visit(pkg)
+
+	return cols
+}
+
+// collectStructCols walks structType's fields, descending into embedded
+// structs. visitedStructs guards against the recursive/self-referential
+// struct shapes found in some third-party packages.
+func collectStructCols(structType *types.Struct, cols map[string]struct{}, visitedStructs map[*types.Struct]bool) {
+	if visitedStructs[structType] {
+		return
+	}
+	visitedStructs[structType] = true
+
+	for i := 0; i < structType.NumFields(); i++ {
+		if col, ok := gencode.ColTag(structType.Tag(i)); ok {
+			cols[col] = struct{}{}
+		}
+		if embedded := gencode.EmbeddedStructOf(structType.Field(i).Type()); embedded != nil {
+			collectStructCols(embedded, cols, visitedStructs)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/hlubek/metaprogramming-go/internal/gencode"
+)
+
+var dupcolAnalyzer = &analysis.Analyzer{
+	Name:     "dupcol",
+	Doc:      "reports struct fields whose col tag duplicates another field's col tag in the same struct",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runDupcol,
+}
+
+func runDupcol(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(n ast.Node) {
+		structType := n.(*ast.StructType)
+
+		seenAt := make(map[string]*ast.Field)
+		for _, field := range structType.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue
+			}
+			col, ok := gencode.ColTag(unquoted)
+			if !ok {
+				continue
+			}
+			if prev, ok := seenAt[col]; ok {
+				pass.Reportf(field.Pos(), "col %q duplicates the tag on field %s", col, fieldName(prev))
+				continue
+			}
+			seenAt[col] = field
+		}
+	})
+
+	return nil, nil
+}
+
+func fieldName(field *ast.Field) string {
+	if len(field.Names) == 0 {
+		if ident, ok := field.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+		return "<embedded>"
+	}
+	names := make([]string, len(field.Names))
+	for i, name := range field.Names {
+		names[i] = name.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/hlubek/metaprogramming-go/internal/gencode"
+)
+
+const generatedMarker = "Code generated by generator, DO NOT EDIT."
+
+var generatedFromPattern = regexp.MustCompile(`^Code generated from ([0-9a-f]+)$`)
+
+var genstaleAnalyzer = &analysis.Analyzer{
+	Name: "genstale",
+	Doc:  "reports generated ChangeSet files whose recorded hash no longer matches the struct they were generated from",
+	Run:  runGenstale,
+}
+
+func runGenstale(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		if file.Doc == nil {
+			continue
+		}
+
+		var generated bool
+		var recordedHash string
+		for _, comment := range file.Doc.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if text == generatedMarker {
+				generated = true
+			}
+			if matches := generatedFromPattern.FindStringSubmatch(text); matches != nil {
+				recordedHash = matches[1]
+			}
+		}
+		if !generated {
+			continue
+		}
+
+		sourceTypeName := changeSetSourceType(file)
+		if sourceTypeName == "" {
+			continue
+		}
+
+		structType, ok := lookupStruct(pass.Pkg, sourceTypeName)
+		if !ok {
+			continue
+		}
+
+		want := gencode.StructHash(structType)
+		switch {
+		case recordedHash == "":
+			pass.Reportf(file.Package, "generated file for %s has no recorded hash; regenerate with go generate", sourceTypeName)
+		case recordedHash != want:
+			pass.Reportf(file.Package, "generated file for %s is out of date (hash %s, want %s); regenerate with go generate", sourceTypeName, recordedHash, want)
+		}
+	}
+
+	return nil, nil
+}
+
+// changeSetSourceType returns the source type name for the first
+// "<Name>ChangeSet" struct declared in file, or "" if it declares none.
+func changeSetSourceType(file *ast.File) string {
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+				continue
+			}
+			if name := strings.TrimSuffix(typeSpec.Name.Name, "ChangeSet"); name != typeSpec.Name.Name {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// lookupStruct finds a named struct type reachable from pkg, directly or
+// through an import, since the source struct (e.g. domain.Product) usually
+// lives in a different package than the generated file referencing it.
+func lookupStruct(pkg *types.Package, name string) (*types.Struct, bool) {
+	visited := make(map[*types.Package]bool)
+
+	var find func(p *types.Package) (*types.Struct, bool)
+	find = func(p *types.Package) (*types.Struct, bool) {
+		if p == nil || visited[p] {
+			return nil, false
+		}
+		visited[p] = true
+
+		if obj, ok := p.Scope().Lookup(name).(*types.TypeName); ok {
+			if structType, ok := obj.Type().Underlying().(*types.Struct); ok {
+				return structType, true
+			}
+		}
+		for _, imp := range p.Imports() {
+			if structType, ok := find(imp); ok {
+				return structType, true
+			}
+		}
+		return nil, false
+	}
+
+	return find(pkg)
+}
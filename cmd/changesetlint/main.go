@@ -0,0 +1,18 @@
+// Command changesetlint is a go/analysis-based linter that catches the
+// ChangeSet generator's class of bugs: a struct declaring the same column
+// twice, repository code referencing a column no struct declares, and a
+// generated file left stale after its source struct changed. It's built to
+// run standalone or as a go vet plugin (`go vet -vettool=changesetlint`).
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(
+		dupcolAnalyzer,
+		colrefAnalyzer,
+		genstaleAnalyzer,
+	)
+}
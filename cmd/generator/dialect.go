@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+	"golang.org/x/tools/go/packages"
+)
+
+// Dialect generates the driver-specific halves of the generated code: how a
+// ChangeSet's fields become query arguments for an UPDATE, and how a table's
+// rows are queried and scanned back into the domain type. Struct walking and
+// type resolution, the ChangeSet type itself and FromPatch are the same for
+// every backend; this is the one seam that varies with the SQL driver a
+// caller wants the generated code to speak.
+type Dialect interface {
+	// generateChangeSetMethods emits the method(s) that turn a ChangeSet
+	// into arguments for an UPDATE statement, in column order.
+	generateChangeSetMethods(f *File, sourceTypeName, changeSetName string, fields []resolvedField)
+	// generateSelectAndScan emits the read side: however the dialect queries
+	// a table and scans rows back, plus Find<Type>ByID/FindAll<Type>s.
+	generateSelectAndScan(f *File, sourceTypePackage, sourceTypeName, tableName string, columns []columnField)
+}
+
+// dialectFor looks up a Dialect by the name given to the -backend flag or a
+// //gen:backend directive.
+func dialectFor(name string) (Dialect, error) {
+	switch name {
+	case "", "squirrel":
+		return squirrelDialect{}, nil
+	case "pgx":
+		return pgxDialect{}, nil
+	case "database/sql":
+		return databaseSQLDialect{}, nil
+	default:
+		return nil, fmt.Errorf(`unknown backend %q, expected "squirrel", "pgx" or "database/sql"`, name)
+	}
+}
+
+var backendDirectivePattern = regexp.MustCompile(`^gen:backend\s+(\S+)$`)
+
+// structDirective returns the backend named by a "//gen:backend <name>"
+// comment directive on typeName's declaration in pkg, or "" if it has none.
+// It lets a source struct pin its own backend so callers don't have to
+// repeat -backend on every go:generate line that targets it.
+func structDirective(pkg *packages.Package, typeName string) string {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != typeName {
+					continue
+				}
+				doc := typeSpec.Doc
+				if doc == nil {
+					doc = genDecl.Doc
+				}
+				if doc == nil {
+					continue
+				}
+				for _, comment := range doc.List {
+					text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+					if matches := backendDirectivePattern.FindStringSubmatch(text); matches != nil {
+						return matches[1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+)
+
+// databaseSQLDialect generates code against the standard library's
+// database/sql, for callers who want a generated ChangeSet without taking on
+// either squirrel or pgx as a dependency.
+type databaseSQLDialect struct{}
+
+// generateChangeSetMethods emits toArgs(), which builds the "col = $1, ..."
+// SET clause and its positional argument slice together, so the two can
+// never drift out of sync with each other.
+func (databaseSQLDialect) generateChangeSetMethods(f *File, sourceTypeName, changeSetName string, fields []resolvedField) {
+	var block []Code
+	block = append(block,
+		Var().Id("setClauses").Index().String(),
+		Var().Id("args").Index().Interface(),
+	)
+
+	for _, rf := range fields {
+		if rf.col == "" {
+			continue
+		}
+
+		// Slice columns are wrapped in pq.Array, the same as the squirrel
+		// backend, since this repo's database/sql usage is always against
+		// lib/pq and a raw Go slice isn't a valid driver.Value on its own.
+		var value Code
+		if rf.isSlice {
+			value = Qual("github.com/lib/pq", "Array").Call(Id("c").Dot(rf.changeSetName))
+		} else {
+			value = Op("*").Id("c").Dot(rf.changeSetName)
+		}
+		block = append(block, If(Id("c").Dot(rf.changeSetName).Op("!=").Nil()).Block(
+			Id("args").Op("=").Append(Id("args"), value),
+			Id("setClauses").Op("=").Append(Id("setClauses"), Qual("fmt", "Sprintf").Call(Lit(rf.col+" = $%d"), Len(Id("args")))),
+		))
+	}
+
+	block = append(block, Return(Qual("strings", "Join").Call(Id("setClauses"), Lit(", ")), Id("args")))
+
+	f.Func().Params(
+		Id("c").Id(changeSetName),
+	).Id("toArgs").Params().Params(String(), Index().Interface()).Block(
+		block...,
+	)
+}
+
+// generateSelectAndScan emits the read-side counterpart of toArgs above: a
+// select<Type>SQL query string, a scan<Type> helper and FindByID/FindAll
+// repository functions built on *sql.Row/*sql.Rows scanning.
+func (databaseSQLDialect) generateSelectAndScan(f *File, sourceTypePackage, sourceTypeName, tableName string, columns []columnField) {
+	domainType := func() *Statement { return Qual(sourceTypePackage, sourceTypeName) }
+
+	colNames := make([]string, len(columns))
+	for i, c := range columns {
+		colNames[i] = c.col
+	}
+	selectSQLName := "select" + sourceTypeName + "SQL"
+	f.Const().Id(selectSQLName).Op("=").Lit("SELECT " + strings.Join(colNames, ", ") + " FROM " + tableName)
+
+	// scan<Type> scans a single row into a domain.<Type> in column order.
+	// *sql.Row and *sql.Rows both satisfy this minimal Scan-only interface.
+	// Slice columns are wrapped in pq.Array on the way out, same as toArgs
+	// wraps them on the way in.
+	scanFuncName := "scan" + sourceTypeName
+	var scanArgs []Code
+	for _, c := range columns {
+		dest := Id("v")
+		for _, part := range c.accessor {
+			dest = dest.Dot(part)
+		}
+		if c.isSlice {
+			scanArgs = append(scanArgs, Qual("github.com/lib/pq", "Array").Call(Op("&").Add(dest)))
+		} else {
+			scanArgs = append(scanArgs, Op("&").Add(dest))
+		}
+	}
+	f.Func().Id(scanFuncName).Params(
+		Id("row").Interface(Id("Scan").Params(Id("dest").Op("...").Interface()).Error()),
+	).Params(domainType(), Error()).Block(
+		Var().Id("v").Add(domainType()),
+		If(
+			Err().Op(":=").Id("row").Dot("Scan").Call(scanArgs...),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(domainType().Values(), Qual("fmt", "Errorf").Call(Lit("scanning "+sourceTypeName+": %w"), Err())),
+		),
+		Return(Id("v"), Nil()),
+	)
+
+	// FindByID looks up a single row by the first mapped column, which is
+	// expected to be the primary key (as in domain.Product.ID).
+	if len(columns) > 0 {
+		pkColumn := columns[0].col
+		findByIDName := "Find" + sourceTypeName + "ByID"
+		f.Func().Id(findByIDName).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("conn").Interface(Id("QueryRowContext").Params(
+				Id("ctx").Qual("context", "Context"),
+				Id("query").String(),
+				Id("args").Op("...").Interface(),
+			).Op("*").Qual("database/sql", "Row")),
+			Id("id").Add(columns[0].typeCode()),
+		).Params(domainType(), Error()).Block(
+			Return(Id(scanFuncName).Call(
+				Id("conn").Dot("QueryRowContext").Call(Id("ctx"), Id(selectSQLName).Op("+").Lit(" WHERE "+pkColumn+" = $1"), Id("id")),
+			)),
+		)
+	}
+
+	// FindAll<Type>s returns every row of the table.
+	findAllName := "FindAll" + sourceTypeName + "s"
+	f.Func().Id(findAllName).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("conn").Interface(Id("QueryContext").Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("query").String(),
+			Id("args").Op("...").Interface(),
+		).Params(Op("*").Qual("database/sql", "Rows"), Error())),
+	).Params(Index().Add(domainType()), Error()).Block(
+		List(Id("rows"), Err()).Op(":=").Id("conn").Dot("QueryContext").Call(Id("ctx"), Id(selectSQLName)),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Qual("fmt", "Errorf").Call(Lit("querying "+sourceTypeName+"s: %w"), Err())),
+		),
+		Defer().Id("rows").Dot("Close").Call(),
+		Var().Id("result").Index().Add(domainType()),
+		For(Id("rows").Dot("Next").Call()).Block(
+			List(Id("v"), Err()).Op(":=").Id(scanFuncName).Call(Id("rows")),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Nil(), Err()),
+			),
+			Id("result").Op("=").Append(Id("result"), Id("v")),
+		),
+		If(
+			Err().Op(":=").Id("rows").Dot("Err").Call(),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id("result"), Nil()),
+	)
+}
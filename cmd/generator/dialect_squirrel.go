@@ -0,0 +1,142 @@
+package main
+
+import (
+	. "github.com/dave/jennifer/jen"
+)
+
+const squirrelPkg = "github.com/Masterminds/squirrel"
+
+// squirrelDialect generates code against github.com/Masterminds/squirrel,
+// the query builder used throughout this repo's hand-written repository
+// code. It's the default backend.
+type squirrelDialect struct{}
+
+// generateChangeSetMethods emits toMap(), the map[string]interface{} that
+// squirrel's SetMap expects, with slice-typed columns wrapped in pq.Array so
+// lib/pq can encode them.
+func (squirrelDialect) generateChangeSetMethods(f *File, sourceTypeName, changeSetName string, fields []resolvedField) {
+	var toMapBlock []Code
+	toMapBlock = append(toMapBlock, Id("m").Op(":=").Make(Map(String()).Interface()))
+
+	for _, rf := range fields {
+		if rf.col == "" {
+			continue
+		}
+
+		var value Code
+		if rf.isSlice {
+			value = Qual("github.com/lib/pq", "Array").Call(Id("c").Dot(rf.changeSetName))
+		} else {
+			value = Op("*").Id("c").Dot(rf.changeSetName)
+		}
+		toMapBlock = append(toMapBlock, If(Id("c").Dot(rf.changeSetName).Op("!=").Nil()).Block(
+			Id("m").Index(Lit(rf.col)).Op("=").Add(value),
+		))
+	}
+
+	toMapBlock = append(toMapBlock, Return(Id("m")))
+
+	f.Func().Params(
+		Id("c").Id(changeSetName),
+	).Id("toMap").Params().Map(String()).Interface().Block(
+		toMapBlock...,
+	)
+}
+
+// generateSelectAndScan emits the read-side counterpart of toMap above: a
+// Select<Type> query builder, a scan<Type> helper and FindByID/FindAll
+// repository functions, so callers don't have to hand-write the mirror of
+// the INSERT/UPDATE code.
+func (squirrelDialect) generateSelectAndScan(f *File, sourceTypePackage, sourceTypeName, tableName string, columns []columnField) {
+	domainType := func() *Statement { return Qual(sourceTypePackage, sourceTypeName) }
+
+	var selectCols []Code
+	for _, c := range columns {
+		selectCols = append(selectCols, Lit(c.col))
+	}
+
+	// Select<Type> builds the base SELECT query for all mapped columns.
+	selectFuncName := "Select" + sourceTypeName
+	f.Func().Id(selectFuncName).Params().Qual(squirrelPkg, "SelectBuilder").Block(
+		Return(Qual(squirrelPkg, "Select").Call(selectCols...).Dot("From").Call(Lit(tableName))),
+	)
+
+	// scan<Type> scans a single row into a domain.<Type> in column order.
+	// Slice columns are wrapped in pq.Array on the way out, same as toMap
+	// wraps them on the way in, since lib/pq can't Scan a Postgres array
+	// straight into a *[]T.
+	scanFuncName := "scan" + sourceTypeName
+	var scanArgs []Code
+	for _, c := range columns {
+		dest := Id("v")
+		for _, part := range c.accessor {
+			dest = dest.Dot(part)
+		}
+		if c.isSlice {
+			scanArgs = append(scanArgs, Qual("github.com/lib/pq", "Array").Call(Op("&").Add(dest)))
+		} else {
+			scanArgs = append(scanArgs, Op("&").Add(dest))
+		}
+	}
+	f.Func().Id(scanFuncName).Params(
+		Id("row").Qual(squirrelPkg, "RowScanner"),
+	).Params(domainType(), Error()).Block(
+		Var().Id("v").Add(domainType()),
+		If(
+			Err().Op(":=").Id("row").Dot("Scan").Call(scanArgs...),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(domainType().Values(), Qual("fmt", "Errorf").Call(Lit("scanning "+sourceTypeName+": %w"), Err())),
+		),
+		Return(Id("v"), Nil()),
+	)
+
+	// FindByID looks up a single row by the first mapped column, which is
+	// expected to be the primary key (as in domain.Product.ID).
+	if len(columns) > 0 {
+		pkColumn := columns[0].col
+		findByIDName := "Find" + sourceTypeName + "ByID"
+		f.Func().Id(findByIDName).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("runner").Qual(squirrelPkg, "BaseRunner"),
+			Id("id").Add(columns[0].typeCode()),
+		).Params(domainType(), Error()).Block(
+			Return(Id(scanFuncName).Call(
+				Id(selectFuncName).Call().
+					Dot("Where").Call(Qual(squirrelPkg, "Eq").Values(Dict{Lit(pkColumn): Id("id")})).
+					Dot("RunWith").Call(Id("runner")).
+					Dot("QueryRowContext").Call(Id("ctx")),
+			)),
+		)
+	}
+
+	// FindAll<Type>s returns every row of the table.
+	findAllName := "FindAll" + sourceTypeName + "s"
+	f.Func().Id(findAllName).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("runner").Qual(squirrelPkg, "BaseRunner"),
+	).Params(Index().Add(domainType()), Error()).Block(
+		List(Id("rows"), Err()).Op(":=").Id(selectFuncName).Call().
+			Dot("RunWith").Call(Id("runner")).
+			Dot("QueryContext").Call(Id("ctx")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Qual("fmt", "Errorf").Call(Lit("querying "+sourceTypeName+"s: %w"), Err())),
+		),
+		Defer().Id("rows").Dot("Close").Call(),
+		Var().Id("result").Index().Add(domainType()),
+		For(Id("rows").Dot("Next").Call()).Block(
+			List(Id("v"), Err()).Op(":=").Id(scanFuncName).Call(Id("rows")),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Nil(), Err()),
+			),
+			Id("result").Op("=").Append(Id("result"), Id("v")),
+		),
+		If(
+			Err().Op(":=").Id("rows").Dot("Err").Call(),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id("result"), Nil()),
+	)
+}
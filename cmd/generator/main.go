@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go/types"
 	"os"
@@ -10,15 +11,25 @@ import (
 
 	. "github.com/dave/jennifer/jen"
 	"golang.org/x/tools/go/packages"
+
+	"github.com/hlubek/metaprogramming-go/internal/gencode"
 )
 
 func main() {
+	backendFlag := flag.String("backend", "", `SQL dialect to generate code for: "squirrel" (default), "pgx" or "database/sql". Overrides any //gen:backend directive on the source struct.`)
+	flag.Parse()
+
 	// Handle arguments to command
-	if len(os.Args) != 2 {
-		failErr(fmt.Errorf("expected exactly one argument: <source type>"))
+	args := flag.Args()
+	if len(args) != 1 && len(args) != 2 {
+		failErr(fmt.Errorf("expected arguments: [-backend=name] <source type> [<table name>]"))
 	}
-	sourceType := os.Args[1]
+	sourceType := args[0]
 	sourceTypePackage, sourceTypeName := splitSourceType(sourceType)
+	var tableName string
+	if len(args) == 2 {
+		tableName = args[1]
+	}
 
 	// Inspect package and use type checker to infer imported types
 	pkg := loadPackage(sourceTypePackage)
@@ -40,17 +51,27 @@ func main() {
 		failErr(fmt.Errorf("type %v is not a struct", obj))
 	}
 
+	backendName := *backendFlag
+	if backendName == "" {
+		backendName = structDirective(pkg, sourceTypeName)
+	}
+	dialect, err := dialectFor(backendName)
+	if err != nil {
+		failErr(err)
+	}
+
 	// Generate code using jennifer
-	err := generate(sourceTypeName, structType)
+	err = generate(sourceTypePackage, sourceTypeName, structType, tableName, dialect)
 	if err != nil {
 		failErr(err)
 	}
 }
 
-// Use a simple regexp pattern to match tag values
-var structColPattern = regexp.MustCompile(`col:"([^"]+)"`)
+// Use a simple regexp pattern to match json tag values; col tags are
+// handled by the shared gencode package.
+var structJSONPattern = regexp.MustCompile(`json:"([^"]*)"`)
 
-func generate(sourceTypeName string, structType *types.Struct) error {
+func generate(sourceTypePackage, sourceTypeName string, structType *types.Struct, tableName string, dialect Dialect) error {
 
 	// Get the package of the file with go:generate comment
 	goPackage := os.Getenv("GOPACKAGE")
@@ -58,31 +79,46 @@ func generate(sourceTypeName string, structType *types.Struct) error {
 	// Start a new file in this package
 	f := NewFile(goPackage)
 
-	// Add a package comment, so IDEs detect files as generated
+	// Add a package comment, so IDEs detect files as generated, and a hash
+	// of the struct's fields/tags, so changesetlint can tell when this file
+	// has drifted out of date relative to the struct it was generated from.
 	f.PackageComment("Code generated by generator, DO NOT EDIT.")
+	f.PackageComment("Code generated from " + gencode.StructHash(structType))
 
-	var (
-		changeSetFields []Code
-	)
+	// Walk the struct fields recursively, flattening embedded structs and
+	// resolving named/pointer/slice/map types to Go code.
+	resolvedFields, walkErrs := walkFields(structType, "", nil)
+	if len(walkErrs) > 0 {
+		return walkErrs
+	}
 
-	// Iterate over struct fields
-	for i := 0; i < structType.NumFields(); i++ {
-		field := structType.Field(i)
+	// The primary key is the first field with a mapped column, the same
+	// convention generateSelectAndScan's FindByID relies on for its WHERE
+	// clause. It's excluded from the ChangeSet/FromPatch below: a PATCH
+	// can't be allowed to rewrite the key used to find the row.
+	pkIndex := -1
+	for i, rf := range resolvedFields {
+		if rf.col != "" {
+			pkIndex = i
+			break
+		}
+	}
 
-		// Generate code for each changeset field
-		code := Id(field.Name())
-		switch v := field.Type().(type) {
-		case *types.Basic:
-			code.Op("*").Id(v.String())
-		case *types.Named:
-			typeName := v.Obj()
-			// Qual automatically imports packages
-			code.Op("*").Qual(
-				typeName.Pkg().Path(),
-				typeName.Name(),
-			)
-		default:
-			return fmt.Errorf("struct field type not hanled: %T", v)
+	var changeSetFields []Code
+	var mutableFields []resolvedField
+	for i, rf := range resolvedFields {
+		if i == pkIndex {
+			continue
+		}
+		mutableFields = append(mutableFields, rf)
+
+		code := Id(rf.changeSetName)
+		if rf.isSlice {
+			// A nil slice is already a meaningful "not set" value, so slice
+			// fields don't need the extra layer of pointer indirection.
+			code.Add(rf.typeCode())
+		} else {
+			code.Op("*").Add(rf.typeCode())
 		}
 		changeSetFields = append(changeSetFields, code)
 	}
@@ -91,38 +127,36 @@ func generate(sourceTypeName string, structType *types.Struct) error {
 	changeSetName := sourceTypeName + "ChangeSet"
 	f.Type().Id(changeSetName).Struct(changeSetFields...)
 
-	// 1. Collect code in toMap() block
-	var toMapBlock []Code
-
-	// 2. Build "m := make(map[string]interface{})"
-	toMapBlock = append(toMapBlock, Id("m").Op(":=").Make(Map(String()).Interface()))
-
-	for i := 0; i < structType.NumFields(); i++ {
-		field := structType.Field(i)
-		tagValue := structType.Tag(i)
-
-		matches := structColPattern.FindStringSubmatch(tagValue)
-		if matches == nil {
+	// Collect the field/column pairs in declaration order, including the
+	// primary key, so the dialect's SELECT and scan code can reuse the exact
+	// same order as its change-set method.
+	var columns []columnField
+	for _, rf := range resolvedFields {
+		if rf.col == "" {
 			continue
 		}
-		col := matches[1]
-
-		// 3. Build "if c.Field != nil { m["col"] = *c.Field }"
-		code := If(Id("c").Dot(field.Name()).Op("!=").Nil()).Block(
-			Id("m").Index(Lit(col)).Op("=").Op("*").Id("c").Dot(field.Name()),
-		)
-		toMapBlock = append(toMapBlock, code)
+		columns = append(columns, columnField{
+			changeSetName: rf.changeSetName,
+			accessor:      rf.accessor,
+			col:           rf.col,
+			isSlice:       rf.isSlice,
+			typeCode:      rf.typeCode,
+		})
 	}
 
-	// 4. Build return statement
-	toMapBlock = append(toMapBlock, Return(Id("m")))
+	// Generate the change-set-to-query-arguments method(s), in whatever
+	// shape the chosen dialect's driver expects.
+	dialect.generateChangeSetMethods(f, sourceTypeName, changeSetName, mutableFields)
 
-	// 5. Build toMap method
-	f.Func().Params(
-		Id("c").Id(changeSetName),
-	).Id("toMap").Params().Map(String()).Interface().Block(
-		toMapBlock...,
-	)
+	// Generate FromPatch, so HTTP handlers can turn a partial JSON body
+	// straight into a ChangeSet.
+	generateFromPatch(f, sourceTypeName, changeSetName, mutableFields)
+
+	// Generate the read side (SELECT/scan) mirroring the write side above,
+	// when a table name was given.
+	if tableName != "" {
+		dialect.generateSelectAndScan(f, sourceTypePackage, sourceTypeName, tableName, columns)
+	}
 
 	// Build the target file name
 	goFile := os.Getenv("GOFILE")
@@ -134,8 +168,250 @@ func generate(sourceTypeName string, structType *types.Struct) error {
 	return f.Save(targetFilename)
 }
 
+// columnField pairs a ChangeSet field with the column name from its
+// `col:"..."` tag, in struct declaration order. accessor is the chain of
+// field names used to read the value off the source struct, which is more
+// than one element deep for fields flattened out of an embedded struct.
+type columnField struct {
+	changeSetName string
+	accessor      []string
+	col           string
+	// isSlice marks columns backed by a slice, which a dialect's scan code
+	// needs to wrap in pq.Array to round-trip what its write side wrapped
+	// going in.
+	isSlice bool
+	// typeCode renders the column's Go type, so FindByID can type its id
+	// parameter after the actual primary key instead of assuming one type.
+	typeCode func() *Statement
+}
+
+// resolvedField is a single leaf field found while walking a source struct,
+// after descending into any embedded structs.
+type resolvedField struct {
+	// changeSetName is the flat field name used in the generated ChangeSet.
+	changeSetName string
+	// accessor is the chain of field names to reach this value on the
+	// source struct, e.g. []string{"Address", "Street"} for a field
+	// flattened out of an embedded struct.
+	accessor []string
+	// typeCode renders the field's Go type, without the optionality
+	// wrapper the ChangeSet adds.
+	typeCode func() *Statement
+	// isSlice marks fields backed by a slice, which don't get the extra
+	// pointer wrapper and get pq.Array-wrapped in toMap.
+	isSlice bool
+	// col is the SQL column name from the field's `col:"..."` tag, or ""
+	// when the field isn't mapped to a column.
+	col string
+	// jsonKey is the key FromPatch matches against, taken from the field's
+	// `json:"..."` tag or, absent that, its col name. Empty when the field
+	// has neither and so can't be addressed from a JSON patch.
+	jsonKey string
+}
+
+// UnhandledFieldError describes a single struct field whose type the field
+// walker doesn't know how to translate into a ChangeSet field.
+type UnhandledFieldError struct {
+	Field string
+	Type  types.Type
+}
+
+func (e UnhandledFieldError) Error() string {
+	return fmt.Sprintf("field %s: type %s not handled", e.Field, e.Type)
+}
+
+// UnhandledFieldsError collects every UnhandledFieldError found while
+// walking a struct, so callers see every problem field at once instead of
+// only the first one.
+type UnhandledFieldsError []UnhandledFieldError
+
+func (e UnhandledFieldsError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fieldErr := range e {
+		msgs[i] = fieldErr.Error()
+	}
+	return fmt.Sprintf("struct field types not handled:\n- %s", strings.Join(msgs, "\n- "))
+}
+
+// walkFields recursively resolves the fields of structType, flattening
+// embedded structs into the result with namePrefix prepended to their
+// ChangeSet field names and accessorPrefix prepended to their access chain
+// on the source struct. It keeps walking past fields it can't resolve and
+// returns every failure it encountered instead of stopping at the first.
+func walkFields(structType *types.Struct, namePrefix string, accessorPrefix []string) ([]resolvedField, UnhandledFieldsError) {
+	var resolved []resolvedField
+	var errs UnhandledFieldsError
+
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+
+		accessor := make([]string, len(accessorPrefix)+1)
+		copy(accessor, accessorPrefix)
+		accessor[len(accessorPrefix)] = field.Name()
+
+		if field.Embedded() {
+			if embeddedStruct := gencode.EmbeddedStructOf(field.Type()); embeddedStruct != nil {
+				nested, nestedErrs := walkFields(embeddedStruct, namePrefix+field.Name(), accessor)
+				resolved = append(resolved, nested...)
+				errs = append(errs, nestedErrs...)
+				continue
+			}
+		}
+
+		typeCode, isSlice, err := resolveFieldType(field.Type())
+		if err != nil {
+			errs = append(errs, UnhandledFieldError{
+				Field: strings.Join(accessor, "."),
+				Type:  field.Type(),
+			})
+			continue
+		}
+
+		tagValue := structType.Tag(i)
+		col := ""
+		if v, ok := gencode.ColTag(tagValue); ok {
+			col = v
+		}
+
+		resolved = append(resolved, resolvedField{
+			changeSetName: namePrefix + field.Name(),
+			accessor:      accessor,
+			typeCode:      typeCode,
+			isSlice:       isSlice,
+			col:           col,
+			jsonKey:       jsonKey(tagValue, col, field.Name()),
+		})
+	}
+
+	return resolved, errs
+}
+
+// jsonKey resolves the key FromPatch should match this field against: the
+// name from a `json:"..."` tag, falling back to the col name, consistent
+// with how encoding/json itself treats a missing/empty tag. A `json:"-"`
+// tag opts the field out, returning "".
+func jsonKey(tagValue, col, fieldName string) string {
+	if matches := structJSONPattern.FindStringSubmatch(tagValue); matches != nil {
+		name := strings.Split(matches[1], ",")[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return fieldName
+		default:
+			return name
+		}
+	}
+	return col
+}
+
+// resolveFieldType recursively resolves a Go type to the jen code for its
+// ChangeSet representation, similar to how gqlgen's binder resolves a Go
+// type against its schema equivalent.
+func resolveFieldType(t types.Type) (typeCode func() *Statement, isSlice bool, err error) {
+	switch v := t.(type) {
+	case *types.Basic:
+		name := v.String()
+		return func() *Statement { return Id(name) }, false, nil
+
+	case *types.Named:
+		// Preserves named types whose underlying type is a basic (e.g.
+		// uuid.UUID, a custom string enum) instead of unwrapping them.
+		typeName := v.Obj()
+		pkgPath, name := typeName.Pkg().Path(), typeName.Name()
+		return func() *Statement { return Qual(pkgPath, name) }, false, nil
+
+	case *types.Pointer:
+		// The field is already optional through its own pointer, so resolve
+		// the pointee as-is instead of adding another layer of pointer.
+		return resolveFieldType(v.Elem())
+
+	case *types.Slice:
+		elemCode, _, err := resolveFieldType(v.Elem())
+		if err != nil {
+			return nil, false, err
+		}
+		return func() *Statement { return Index().Add(elemCode()) }, true, nil
+
+	case *types.Map:
+		keyCode, _, err := resolveFieldType(v.Key())
+		if err != nil {
+			return nil, false, err
+		}
+		valueCode, _, err := resolveFieldType(v.Elem())
+		if err != nil {
+			return nil, false, err
+		}
+		return func() *Statement { return Map(keyCode()).Add(valueCode()) }, false, nil
+
+	default:
+		return nil, false, fmt.Errorf("struct field type not handled: %T", v)
+	}
+}
+
+// generateFromPatch emits a FromPatch method that fills in a ChangeSet from
+// a partial JSON body already decoded into a map of raw field values, e.g.
+// from an HTTP PATCH handler, plus an UnknownPatchKeysError type it returns
+// when the patch contains keys no field maps to.
+func generateFromPatch(f *File, sourceTypeName, changeSetName string, resolvedFields []resolvedField) {
+	unknownKeysErrorName := "Unknown" + sourceTypeName + "PatchKeysError"
+
+	var cases []Code
+	for _, rf := range resolvedFields {
+		if rf.jsonKey == "" {
+			continue
+		}
+
+		assign := Id("c").Dot(rf.changeSetName).Op("=").Id("v")
+		if !rf.isSlice {
+			assign = Id("c").Dot(rf.changeSetName).Op("=").Op("&").Id("v")
+		}
+
+		cases = append(cases, Case(Lit(rf.jsonKey)).Block(
+			Var().Id("v").Add(rf.typeCode()),
+			If(
+				Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("raw"), Op("&").Id("v")),
+				Err().Op("!=").Nil(),
+			).Block(
+				Return(Qual("fmt", "Errorf").Call(Lit("unmarshaling patch key %q: %w"), Id("key"), Err())),
+			),
+			assign,
+		))
+	}
+	cases = append(cases, Default().Block(
+		Id("unknown").Op("=").Append(Id("unknown"), Id("key")),
+	))
+
+	f.Func().Params(
+		Id("c").Op("*").Id(changeSetName),
+	).Id("FromPatch").Params(
+		Id("patch").Map(String()).Qual("encoding/json", "RawMessage"),
+	).Error().Block(
+		Var().Id("unknown").Index().String(),
+		For(List(Id("key"), Id("raw")).Op(":=").Range().Id("patch")).Block(
+			Switch(Id("key")).Block(cases...),
+		),
+		If(Len(Id("unknown")).Op(">").Lit(0)).Block(
+			Return(Id(unknownKeysErrorName).Call(Id("unknown"))),
+		),
+		Return(Nil()),
+	)
+
+	// UnknownPatchKeysError lists JSON keys from a patch that don't map to
+	// any field of the ChangeSet.
+	f.Type().Id(unknownKeysErrorName).Index().String()
+	f.Func().Params(
+		Id("e").Id(unknownKeysErrorName),
+	).Id("Error").Params().String().Block(
+		Return(Qual("fmt", "Sprintf").Call(
+			Lit("unknown patch keys: %s"),
+			Qual("strings", "Join").Call(Id("e"), Lit(", ")),
+		)),
+	)
+}
+
 func loadPackage(path string) *packages.Package {
-	cfg := &packages.Config{Mode: packages.NeedTypes | packages.NeedImports}
+	cfg := &packages.Config{Mode: packages.NeedTypes | packages.NeedImports | packages.NeedSyntax | packages.NeedName | packages.NeedDeps}
 	pkgs, err := packages.Load(cfg, path)
 	if err != nil {
 		failErr(fmt.Errorf("loading packages for inspection: %v", err))
@@ -0,0 +1,85 @@
+// Package gencode holds the bits of struct-tag and type inspection logic
+// shared between the generator (cmd/generator) and the drift checker
+// (cmd/changesetlint), so the two always agree on what a col tag means and
+// what a struct's generated mirror was hashed from.
+package gencode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+var colTagPattern = regexp.MustCompile(`col:"([^"]+)"`)
+
+// ColTag returns the column name from a struct field's `col:"..."` tag
+// value (already unquoted, as returned by types.Struct.Tag), and whether
+// the tag carries a col entry at all.
+func ColTag(tag string) (string, bool) {
+	matches := colTagPattern.FindStringSubmatch(tag)
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// EmbeddedStructOf returns the struct type underlying an embedded field, be
+// it embedded by value or by pointer, or nil if t isn't an embedded struct.
+func EmbeddedStructOf(t types.Type) *types.Struct {
+	switch v := t.(type) {
+	case *types.Named:
+		if s, ok := v.Underlying().(*types.Struct); ok {
+			return s
+		}
+	case *types.Pointer:
+		if named, ok := v.Elem().(*types.Named); ok {
+			if s, ok := named.Underlying().(*types.Struct); ok {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// StructHash hashes the name, type and tag of every field of structType, in
+// declaration order, into a short hex digest, descending into embedded
+// structs the same way the generator's own field walker does so the hash
+// covers what actually gets generated. The generator records this in a
+// generated file's header comment so changesetlint can later detect that the
+// struct and its generated mirror have drifted apart.
+func StructHash(structType *types.Struct) string {
+	var b strings.Builder
+	writeStructHash(&b, structType, make(map[*types.Struct]bool))
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// writeStructHash is the recursive half of StructHash. visitedStructs guards
+// against the recursive/self-referential struct shapes collectStructCols
+// also has to guard against.
+func writeStructHash(b *strings.Builder, structType *types.Struct, visitedStructs map[*types.Struct]bool) {
+	if visitedStructs[structType] {
+		return
+	}
+	visitedStructs[structType] = true
+
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+
+		if field.Embedded() {
+			if embedded := EmbeddedStructOf(field.Type()); embedded != nil {
+				writeStructHash(b, embedded, visitedStructs)
+				continue
+			}
+		}
+
+		b.WriteString(field.Name())
+		b.WriteByte(':')
+		b.WriteString(field.Type().String())
+		b.WriteByte(':')
+		b.WriteString(structType.Tag(i))
+		b.WriteByte('\n')
+	}
+}